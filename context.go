@@ -0,0 +1,66 @@
+package main
+
+import "strings"
+
+// enumInfo is what an enum attribute resolves to: the Go type name it was
+// translated under (e.g. "SurfaceError") and whether it is a bitfield.
+type enumInfo struct {
+	Name     string
+	BitField bool
+}
+
+// Context owns the state shared across the translation and emit phases of a
+// single generator run: the symbol table mapping Wayland wire names (e.g.
+// "wl_surface") to their Go identifiers ("Surface"), built from every input
+// protocol before any interface is translated so that an arg in one
+// protocol can reference an interface declared in another. enums is a
+// second, interface-qualified table for resolving an arg's enum attribute,
+// since enum names like "error" are reused across many interfaces and a
+// flat name table would collide.
+type Context struct {
+	names map[string]string
+	enums map[string]enumInfo // "wl_iface_name.enum_name" -> enumInfo
+}
+
+func NewContext() *Context {
+	return &Context{
+		names: make(map[string]string),
+		enums: make(map[string]enumInfo),
+	}
+}
+
+// Register maps a Wayland wire name to its Go identifier and returns it.
+func (ctx *Context) Register(wlName string) string {
+	goName := CamelCase(wlName)
+	ctx.names[wlName] = goName
+	return goName
+}
+
+// Name resolves a wire name registered by Register, wherever in the input
+// set it was declared. It returns "" if wlName was never registered.
+func (ctx *Context) Name(wlName string) string {
+	return ctx.names[wlName]
+}
+
+// RegisterEnum records the enum declared as enumWlName on interface
+// ifaceWlName, so that an <arg enum="..."> elsewhere can resolve it.
+func (ctx *Context) RegisterEnum(ifaceWlName, enumWlName string, bitField bool) {
+	ctx.enums[ifaceWlName+"."+enumWlName] = enumInfo{
+		Name:     ctx.Name(ifaceWlName) + CamelCase(enumWlName),
+		BitField: bitField,
+	}
+}
+
+// ResolveEnum looks up an arg's enum attribute, which names either a local
+// enum ("name") or one declared on another interface ("iface.name").
+// ifaceWlName is the wire name of the interface the arg itself belongs to,
+// used to qualify a local reference. It returns ok=false if the enum was
+// never registered.
+func (ctx *Context) ResolveEnum(ifaceWlName, enumAttr string) (enumInfo, bool) {
+	key := enumAttr
+	if !strings.Contains(enumAttr, ".") {
+		key = ifaceWlName + "." + enumAttr
+	}
+	info, ok := ctx.enums[key]
+	return info, ok
+}
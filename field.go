@@ -0,0 +1,83 @@
+package main
+
+import "fmt"
+
+// Field is a single request or event argument. It carries the raw wire
+// attributes alongside the resolved Type, and knows how to render itself in
+// each of the forms the emit phase needs: a Go parameter declaration, an
+// event struct field, and the expression that produces its word in the
+// marshalled request body.
+type Field struct {
+	Name      string
+	WlType    string // wayland wire type name: int, uint, string, fd, fixed, array, object, new_id
+	Interface string // wire name of the referenced interface, if any
+	Enum      string // "name" or "iface.name" if this arg is enum-typed, else ""
+	AllowNull bool
+	Type      Type // resolved Go type; nil only for a new_id with no interface
+}
+
+// IsNewID reports whether this field is a new_id argument. A new_id bound to
+// a known interface (the common case) returns the newly created proxy
+// instead of appearing in the parameter list; a new_id with no interface
+// (wl_registry.bind) instead expands into three parameters: iface, version
+// and a Proxy to fill in.
+func (f *Field) IsNewID() bool {
+	return f.WlType == "new_id"
+}
+
+// Declaration renders the field as a Go request parameter. It returns "" for
+// a bound new_id, since that case returns the created proxy instead of
+// taking it as a parameter.
+func (f *Field) Declaration() string {
+	if f.IsNewID() {
+		if f.Type == nil {
+			return fmt.Sprintf("iface string, version uint32, %s Proxy", f.Name)
+		}
+		return ""
+	}
+	return fmt.Sprintf("%s %s", f.Name, f.Type.GoType())
+}
+
+// EventFieldType renders the field as the type of an event struct field.
+func (f *Field) EventFieldType() string {
+	return f.Type.GoType()
+}
+
+// WireKind is how a field is physically represented on the wire: as a
+// single 32-bit word, as a length-prefixed and 4-byte padded blob, or
+// out-of-band as a file descriptor.
+type WireKind int
+
+const (
+	WireWord WireKind = iota // int, uint, fixed, enum, object, new_id: one uint32
+	WireBlob                 // string, array: uint32 length + padded data
+	WireFD                   // fd: sent out-of-band via SCM_RIGHTS, absent from the body
+)
+
+func (f *Field) WireKind() WireKind {
+	switch f.WlType {
+	case "string", "array":
+		return WireBlob
+	case "fd":
+		return WireFD
+	default:
+		return WireWord
+	}
+}
+
+// WireWordExpr returns the Go expression that produces this field's 32-bit
+// wire word, given localName as the Go value holding it (the parameter name
+// for most fields, or the newly created proxy's variable name for a bound
+// new_id). It only applies when WireKind() == WireWord.
+func (f *Field) WireWordExpr(localName string) string {
+	switch f.Type.(type) {
+	case *InterfaceType:
+		return fmt.Sprintf("proxyID(Proxy(%s))", localName)
+	case *EnumType:
+		return fmt.Sprintf("uint32(%s)", localName)
+	}
+	if f.WlType == "int" || f.WlType == "fixed" {
+		return fmt.Sprintf("uint32(%s)", localName)
+	}
+	return localName
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Generate emits one formatted Go file per protocol into outDir, in package
+// outPackage, importing wlImport for the Proxy/BaseProxy/Connection runtime
+// types it relies on, plus the shared wire-format support file (Fixed,
+// push*/pop* encoders) they all call into. outPath overrides the default
+// per-protocol file name, and only applies when there is exactly one
+// protocol to generate.
+func (ctx *Context) Generate(protocols []*Protocol, outDir, outPath, outPackage, wlImport string) error {
+	if err := writeWireSupport(outDir, outPackage, wlImport); err != nil {
+		return err
+	}
+
+	for _, p := range protocols {
+		path := filepath.Join(outDir, outputName(p.Name))
+		if outPath != "" && len(protocols) == 1 {
+			path = outPath
+		}
+		if err := ctx.generateProtocol(p, path, outPackage, wlImport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctx *Context) generateProtocol(p *Protocol, outPath, outPackage, wlImport string) error {
+	var (
+		constBuffer    bytes.Buffer
+		ifaceBuffer    bytes.Buffer
+		reqCodesBuffer bytes.Buffer
+	)
+
+	var hasRequests, hasEvents bool
+	for _, iface := range p.Interfaces {
+		if len(iface.Requests) > 0 {
+			hasRequests = true
+		}
+		if len(iface.Events) > 0 {
+			hasEvents = true
+		}
+	}
+
+	constBuffer.WriteString(fmt.Sprintf("package %s\n", outPackage))
+	constBuffer.WriteString("\nimport (\n")
+	if hasRequests {
+		constBuffer.WriteString("\t\"bytes\"\n")
+	}
+	if hasEvents {
+		constBuffer.WriteString("\t\"fmt\"\n")
+	}
+	constBuffer.WriteString(fmt.Sprintf("\n\t. \"%s\"\n)\n", wlImport))
+
+	reqCodesBuffer.WriteString("\n//Interface Request Codes\n") // request codes
+	reqCodesBuffer.WriteString("\nconst (\n")                   // request codes
+
+	for _, iface := range p.Interfaces {
+		ctx.emitInterface(iface, &constBuffer, &ifaceBuffer, &reqCodesBuffer)
+	}
+
+	reqCodesBuffer.WriteString(")") // request codes end
+
+	var src bytes.Buffer
+	constBuffer.WriteTo(&src)
+	reqCodesBuffer.WriteTo(&src)
+	ifaceBuffer.WriteTo(&src)
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: %w", p.Name, annotateFormatError(src.Bytes(), err))
+	}
+
+	return os.WriteFile(outPath, formatted, 0644)
+}
+
+// annotateFormatError turns a go/format syntax error into one that shows the
+// offending generated lines in context, since without the surrounding code
+// a bare "expected declaration, found..." is useless for tracking down
+// which emit* call produced it.
+func annotateFormatError(src []byte, err error) error {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return err
+	}
+
+	lines := strings.Split(string(src), "\n")
+	var msg bytes.Buffer
+	for _, e := range list {
+		fmt.Fprintf(&msg, "\n%s\n", e)
+		start, end := e.Pos.Line-3, e.Pos.Line+2
+		if start < 1 {
+			start = 1
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for i := start; i <= end; i++ {
+			marker := "  "
+			if i == e.Pos.Line {
+				marker = "->"
+			}
+			fmt.Fprintf(&msg, "%s %4d| %s\n", marker, i, lines[i-1])
+		}
+	}
+
+	return errors.New(msg.String())
+}
+
+func (ctx *Context) emitInterface(iface *Interface, constBuffer, ifaceBuffer, reqCodesBuffer *bytes.Buffer) {
+	constBuffer.WriteString(fmt.Sprintf("\nconst (\n%sInterfaceName = \"%s\"\n%sInterfaceVersion = %d\n)\n",
+		iface.Name, iface.WlName, iface.Name, iface.Version))
+
+	var eventBuffer bytes.Buffer
+	var eventNames []string
+
+	// Event struct types
+	for _, event := range iface.Events {
+		typeName := iface.Name + event.Name + "Event"
+		if event.Since > 1 {
+			eventBuffer.WriteString(fmt.Sprintf("\nconst %s%sSinceVersion = %d\n", iface.Name, event.Name, event.Since))
+		}
+		eventBuffer.WriteString(fmt.Sprintf("\ntype %s struct {\n", typeName))
+		for _, f := range event.Fields {
+			eventBuffer.WriteString(fmt.Sprintf("%s %s\n", CamelCase(f.Name), f.EventFieldType()))
+		}
+		eventBuffer.WriteString("}\n")
+		eventNames = append(eventNames, event.Name)
+	}
+	eventBuffer.WriteTo(ifaceBuffer)
+
+	// interface type definition
+	ifaceBuffer.WriteString(fmt.Sprintf("\ntype %s struct {\n", iface.Name))
+	ifaceBuffer.WriteString("BaseProxy\n")
+	for _, evName := range eventNames {
+		ifaceBuffer.WriteString(fmt.Sprintf("%s chan %s\n", evName+"Chan", iface.Name+evName+"Event"))
+	}
+	ifaceBuffer.WriteString("}\n")
+
+	// interface constructor
+	ifaceBuffer.WriteString(fmt.Sprintf("\nfunc New%s(conn *Connection) *%s {\n", iface.Name, iface.Name))
+	ifaceBuffer.WriteString(fmt.Sprintf("ret := new(%s)\n", iface.Name))
+	for _, evName := range eventNames {
+		ifaceBuffer.WriteString(fmt.Sprintf("ret.%s = make(chan %s)\n", evName+"Chan", iface.Name+evName+"Event"))
+	}
+	ifaceBuffer.WriteString("conn.Register(ret)\n")
+	ifaceBuffer.WriteString("return ret\n")
+	ifaceBuffer.WriteString("}\n")
+
+	// interface method definitions (requests)
+	// order used for request identification
+	for order, req := range iface.Requests {
+		reqCodeName := strings.ToTitle(fmt.Sprintf("_%s_%s", iface.Name, req.Name)) // first _ for not export constant
+		reqCodesBuffer.WriteString(fmt.Sprintf("%s = %d\n", reqCodeName, order))
+		ctx.emitRequest(iface, req, reqCodeName, ifaceBuffer)
+	}
+
+	ctx.emitDispatch(iface, ifaceBuffer)
+
+	for _, enum := range iface.Enums {
+		ctx.emitEnum(iface, enum, constBuffer)
+	}
+}
+
+func (ctx *Context) emitRequest(iface *Interface, req *Request, reqCodeName string, out *bytes.Buffer) {
+	if req.Since > 1 {
+		out.WriteString(fmt.Sprintf("\nconst %s%sSinceVersion = %d\n", iface.Name, req.Name, req.Since))
+	}
+
+	out.WriteString(fmt.Sprintf("\nfunc (p *%s) %s(", iface.Name, req.Name))
+
+	var args []string
+	for _, f := range req.Fields {
+		if d := f.Declaration(); d != "" {
+			args = append(args, d)
+		}
+	}
+	out.WriteString(strings.Join(args, ","))
+	out.WriteString(")") // close the args
+
+	// a bound new_id arg (the common case) returns the created proxy on top
+	// of the usual error; an unbound one (wl_registry.bind) does not.
+	var boundNewID *Field
+	for _, f := range req.Fields {
+		if f.IsNewID() && f.Type != nil {
+			boundNewID = f
+			break
+		}
+	}
+
+	rets := []string{" error"}
+	if boundNewID != nil {
+		rets = append([]string{boundNewID.Type.GoType()}, rets...)
+	}
+	if len(rets) > 1 {
+		out.WriteString("(" + strings.Join(rets, ",") + ")")
+	} else {
+		out.WriteString(rets[0])
+	}
+	out.WriteString("{\n")
+
+	if req.Since > 1 {
+		errRet := "ErrVersionUnsupported"
+		if boundNewID != nil {
+			errRet = "nil, " + errRet
+		}
+		out.WriteString(fmt.Sprintf("if p.Version() < %d {\nreturn %s\n}\n", req.Since, errRet))
+	}
+
+	hasRetType := ""
+	if boundNewID != nil {
+		it := boundNewID.Type.(*InterfaceType)
+		out.WriteString(fmt.Sprintf("ret := New%s(p.Connection())\n", it.Name))
+		hasRetType = "ret,"
+	}
+
+	// Marshal the request body word-by-word, per Wayland's wire format:
+	// 32-bit aligned words, with string/array length-prefixed and padded,
+	// and fd passed out-of-band via fds rather than in the message body.
+	out.WriteString("buf := new(bytes.Buffer)\n")
+	var fdNames []string
+	for _, f := range req.Fields {
+		switch {
+		case f.IsNewID() && f.Type == nil:
+			out.WriteString("pushString(buf, iface)\n")
+			out.WriteString("pushUint32(buf, version)\n")
+			out.WriteString(fmt.Sprintf("pushUint32(buf, proxyID(%s))\n", f.Name))
+		case f.IsNewID():
+			out.WriteString("pushUint32(buf, proxyID(Proxy(ret)))\n")
+		case f.WireKind() == WireFD:
+			fdNames = append(fdNames, f.Name)
+		case f.WireKind() == WireBlob:
+			if f.WlType == "string" {
+				out.WriteString(fmt.Sprintf("pushString(buf, %s)\n", f.Name))
+			} else {
+				out.WriteString(fmt.Sprintf("pushArray(buf, %s)\n", f.Name))
+			}
+		default:
+			out.WriteString(fmt.Sprintf("pushUint32(buf, %s)\n", f.WireWordExpr(f.Name)))
+		}
+	}
+
+	fdsExpr := "nil"
+	if len(fdNames) > 0 {
+		fdsExpr = fmt.Sprintf("[]uintptr{%s}", strings.Join(fdNames, ","))
+	}
+
+	out.WriteString(fmt.Sprintf("return %s p.Connection().SendRequest(p,%s,buf.Bytes(),%s)", hasRetType, reqCodeName, fdsExpr))
+
+	out.WriteString("\n}\n")
+}
+
+// emitDispatch generates the per-interface Dispatch method that decodes an
+// incoming event's wire bytes into its *Event struct and sends the result
+// on the matching channel. Opcodes match event declaration order, same as
+// request codes.
+func (ctx *Context) emitDispatch(iface *Interface, out *bytes.Buffer) {
+	if len(iface.Events) == 0 {
+		return
+	}
+
+	needsOffset := false
+	for _, ev := range iface.Events {
+		for _, f := range ev.Fields {
+			if f.WireKind() != WireFD {
+				needsOffset = true
+			}
+		}
+	}
+
+	out.WriteString(fmt.Sprintf("\nfunc (p *%s) Dispatch(opcode uint16, data []byte, fds []uintptr) error {\n", iface.Name))
+	if needsOffset {
+		out.WriteString("offset := 0\n")
+	}
+	out.WriteString("switch opcode {\n")
+
+	for order, ev := range iface.Events {
+		out.WriteString(fmt.Sprintf("case %d: // %s\n", order, ev.WlName))
+		out.WriteString(fmt.Sprintf("var ev %s%sEvent\n", iface.Name, ev.Name))
+
+		fdIndex := 0
+		for _, f := range ev.Fields {
+			goName := CamelCase(f.Name)
+			switch f.WireKind() {
+			case WireFD:
+				out.WriteString(fmt.Sprintf("ev.%s = fds[%d]\n", goName, fdIndex))
+				fdIndex++
+			case WireBlob:
+				if f.WlType == "string" {
+					out.WriteString(fmt.Sprintf("ev.%s, offset = popString(data, offset)\n", goName))
+				} else {
+					out.WriteString(fmt.Sprintf("ev.%s, offset = popArray(data, offset)\n", goName))
+				}
+			default:
+				ctx.emitWordDecode(f, goName, out)
+			}
+		}
+
+		out.WriteString(fmt.Sprintf("p.%sChan <- ev\n", ev.Name))
+	}
+
+	out.WriteString("default:\n")
+	out.WriteString(fmt.Sprintf("return fmt.Errorf(\"%s.Dispatch: unknown event opcode %%d\", opcode)\n", iface.Name))
+	out.WriteString("}\n")
+	out.WriteString("return nil\n")
+	out.WriteString("}\n")
+}
+
+func (ctx *Context) emitWordDecode(f *Field, goName string, out *bytes.Buffer) {
+	switch t := f.Type.(type) {
+	case *InterfaceType:
+		if t.Name == "" {
+			out.WriteString(fmt.Sprintf("ev.%s = p.Connection().Object(popUint32(data, offset))\n", goName))
+		} else {
+			out.WriteString(fmt.Sprintf("ev.%s, _ = p.Connection().Object(popUint32(data, offset)).(*%s)\n", goName, t.Name))
+		}
+		out.WriteString("offset += 4\n")
+		return
+	case *EnumType:
+		out.WriteString(fmt.Sprintf("ev.%s = %s(popUint32(data, offset))\n", goName, t.Name))
+		out.WriteString("offset += 4\n")
+		return
+	}
+
+	switch f.WlType {
+	case "int":
+		out.WriteString(fmt.Sprintf("ev.%s = int32(popUint32(data, offset))\n", goName))
+	case "fixed":
+		out.WriteString(fmt.Sprintf("ev.%s = Fixed(popUint32(data, offset))\n", goName))
+	default: // uint, enum
+		out.WriteString(fmt.Sprintf("ev.%s = popUint32(data, offset)\n", goName))
+	}
+	out.WriteString("offset += 4\n")
+}
+
+func (ctx *Context) emitEnum(iface *Interface, enum *Enum, out *bytes.Buffer) {
+	constTypeName := iface.Name + enum.Name
+	out.WriteString(fmt.Sprintf("\ntype %s uint\n", constTypeName)) // enums are uint
+	out.WriteString("const (\n")
+	for _, entry := range enum.Entries {
+		constName := iface.Name + enum.Name + entry.Name
+		out.WriteString(fmt.Sprintf("%s %s = %s\n", constName, constTypeName, entry.Value))
+	}
+	out.WriteString(")\n")
+
+	if enum.BitField {
+		out.WriteString(fmt.Sprintf("\nfunc (e %s) Or(other %s) %s {\nreturn e | other\n}\n", constTypeName, constTypeName, constTypeName))
+		out.WriteString(fmt.Sprintf("\nfunc (e %s) And(other %s) %s {\nreturn e & other\n}\n", constTypeName, constTypeName, constTypeName))
+		out.WriteString(fmt.Sprintf("\nfunc (e %s) Has(flag %s) bool {\nreturn e&flag == flag\n}\n", constTypeName, constTypeName))
+	}
+}
+
+// outputName derives the generated file name from a protocol name, e.g.
+// "xdg_shell" -> "xdg_shell.ba.go". The ".ba" (bindings) suffix keeps
+// generated files visually distinct from hand-written ones in the same
+// package, the way xgb-generated files carry a ".xgb.go" suffix.
+func outputName(protocolName string) string {
+	return strings.Replace(protocolName, "-", "_", -1) + ".ba.go"
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+)
+
+// The xml* types mirror the wayland protocol XML schema exactly, for
+// decoding with encoding/xml. Translate converts a tree of these into the
+// typed AST (Protocol, Interface, Request, Event, Enum, Field, ...) that the
+// rest of the generator works with.
+type (
+	xmlProtocol struct {
+		XMLName    xml.Name       `xml:"protocol"`
+		Name       string         `xml:"name,attr"`
+		Copyright  string         `xml:"copyright"`
+		Interfaces []xmlInterface `xml:"interface"`
+	}
+
+	xmlDescription struct {
+		XMLName     xml.Name `xml:"description"`
+		Summary     string   `xml:"summary,attr"`
+		Description string   `xml:"description"`
+	}
+
+	xmlInterface struct {
+		XMLName     xml.Name       `xml:"interface"`
+		Name        string         `xml:"name,attr"`
+		Version     int            `xml:"version,attr"`
+		Since       int            `xml:"since,attr"`
+		Description xmlDescription `xml:"description"`
+		Requests    []xmlRequest   `xml:"request"`
+		Events      []xmlEvent     `xml:"event"`
+		Enums       []xmlEnum      `xml:"enum"`
+	}
+
+	xmlRequest struct {
+		XMLName     xml.Name       `xml:"request"`
+		Name        string         `xml:"name,attr"`
+		Type        string         `xml:"type,attr"`
+		Since       int            `xml:"since,attr"`
+		Description xmlDescription `xml:"description"`
+		Args        []xmlArg       `xml:"arg"`
+	}
+
+	xmlArg struct {
+		XMLName   xml.Name `xml:"arg"`
+		Name      string   `xml:"name,attr"`
+		Type      string   `xml:"type,attr"`
+		Interface string   `xml:"interface,attr"`
+		Enum      string   `xml:"enum,attr"`
+		AllowNull bool     `xml:"allow-null,attr"`
+		Summary   string   `xml:"summary,attr"`
+	}
+
+	xmlEvent struct {
+		XMLName     xml.Name       `xml:"event"`
+		Name        string         `xml:"name,attr"`
+		Since       int            `xml:"since,attr"`
+		Description xmlDescription `xml:"description"`
+		Args        []xmlArg       `xml:"arg"`
+	}
+
+	xmlEnum struct {
+		XMLName     xml.Name       `xml:"enum"`
+		Name        string         `xml:"name,attr"`
+		BitField    bool           `xml:"bitfield,attr"`
+		Description xmlDescription `xml:"description"`
+		Entries     []xmlEntry     `xml:"entry"`
+	}
+
+	xmlEntry struct {
+		XMLName xml.Name `xml:"entry"`
+		Name    string   `xml:"name,attr"`
+		Value   string   `xml:"value,attr"`
+		Summary string   `xml:"summary,attr"`
+	}
+)
+
+// ParseXML decodes a single protocol XML file.
+func ParseXML(path string) (xmlProtocol, error) {
+	var protocol xmlProtocol
+
+	f, err := os.Open(path)
+	if err != nil {
+		return protocol, err
+	}
+	defer f.Close()
+
+	if err := xml.NewDecoder(f).Decode(&protocol); err != nil {
+		return protocol, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return protocol, nil
+}
+
+// Protocol, Interface, Request, Event, Enum and EnumEntry are the typed AST
+// produced by Translate. Unlike their xml* counterparts, names are already
+// resolved to Go identifiers and arguments have been resolved to Fields
+// carrying a concrete Type.
+type (
+	Protocol struct {
+		Name       string
+		Interfaces []*Interface
+	}
+
+	Interface struct {
+		Name    string // Go identifier, e.g. "Surface"
+		WlName  string // wire name, e.g. "wl_surface"
+		Version int
+		Since   int
+
+		Requests []*Request
+		Events   []*Event
+		Enums    []*Enum
+	}
+
+	Request struct {
+		Name   string // Go identifier
+		WlName string
+		Type   string // "" or "destructor"
+		Since  int
+		Fields []*Field
+	}
+
+	Event struct {
+		Name   string // Go identifier
+		WlName string
+		Since  int
+		Fields []*Field
+	}
+
+	Enum struct {
+		Name     string // Go identifier
+		WlName   string
+		BitField bool
+		Entries  []*EnumEntry
+	}
+
+	EnumEntry struct {
+		Name  string // Go identifier
+		Value string
+	}
+)
+
+// Translate converts decoded protocol XML into the generator's AST,
+// resolving every interface name and enum against ctx before translating
+// any interface body. That two-pass structure is what lets a request or
+// event argument in one protocol reference an interface or enum declared
+// in another.
+func Translate(ctx *Context, xmlProtocols []xmlProtocol) []*Protocol {
+	for _, xp := range xmlProtocols {
+		for _, xi := range xp.Interfaces {
+			ctx.Register(xi.Name)
+		}
+	}
+	for _, xp := range xmlProtocols {
+		for _, xi := range xp.Interfaces {
+			for _, xn := range xi.Enums {
+				ctx.RegisterEnum(xi.Name, xn.Name, xn.BitField)
+			}
+		}
+	}
+
+	protocols := make([]*Protocol, 0, len(xmlProtocols))
+	for _, xp := range xmlProtocols {
+		protocols = append(protocols, translateProtocol(ctx, xp))
+	}
+	return protocols
+}
+
+func translateProtocol(ctx *Context, xp xmlProtocol) *Protocol {
+	p := &Protocol{Name: xp.Name}
+	for _, xi := range xp.Interfaces {
+		p.Interfaces = append(p.Interfaces, translateInterface(ctx, xi))
+	}
+	return p
+}
+
+func translateInterface(ctx *Context, xi xmlInterface) *Interface {
+	iface := &Interface{
+		Name:    ctx.Name(xi.Name),
+		WlName:  xi.Name,
+		Version: xi.Version,
+		Since:   xi.Since,
+	}
+
+	for _, xr := range xi.Requests {
+		iface.Requests = append(iface.Requests, translateRequest(ctx, xi.Name, xr))
+	}
+	for _, xe := range xi.Events {
+		iface.Events = append(iface.Events, translateEvent(ctx, xi.Name, xe))
+	}
+	for _, xn := range xi.Enums {
+		iface.Enums = append(iface.Enums, translateEnum(ctx, xn))
+	}
+
+	return iface
+}
+
+func translateRequest(ctx *Context, ifaceWlName string, xr xmlRequest) *Request {
+	r := &Request{
+		Name:   CamelCase(xr.Name),
+		WlName: xr.Name,
+		Type:   xr.Type,
+		Since:  xr.Since,
+	}
+	for _, xa := range xr.Args {
+		r.Fields = append(r.Fields, translateField(ctx, ifaceWlName, xa))
+	}
+	return r
+}
+
+func translateEvent(ctx *Context, ifaceWlName string, xe xmlEvent) *Event {
+	e := &Event{
+		Name:   ctx.Register(xe.Name),
+		WlName: xe.Name,
+		Since:  xe.Since,
+	}
+	for _, xa := range xe.Args {
+		e.Fields = append(e.Fields, translateField(ctx, ifaceWlName, xa))
+	}
+	return e
+}
+
+func translateEnum(ctx *Context, xn xmlEnum) *Enum {
+	e := &Enum{
+		Name:     ctx.Register(xn.Name),
+		WlName:   xn.Name,
+		BitField: xn.BitField,
+	}
+	for _, xt := range xn.Entries {
+		e.Entries = append(e.Entries, &EnumEntry{
+			Name:  ctx.Register(xt.Name),
+			Value: xt.Value,
+		})
+	}
+	return e
+}
+
+// translateField resolves a single arg into a Field with a concrete Type.
+// An enum attribute takes priority over the arg's raw wire type, so that a
+// "uint" arg declared enum="wl_output.transform" is typed as OutputTransform
+// rather than uint32.
+func translateField(ctx *Context, ifaceWlName string, xa xmlArg) *Field {
+	f := &Field{
+		Name:      xa.Name,
+		WlType:    xa.Type,
+		Interface: xa.Interface,
+		Enum:      xa.Enum,
+		AllowNull: xa.AllowNull,
+	}
+
+	if xa.Enum != "" {
+		if info, ok := ctx.ResolveEnum(ifaceWlName, xa.Enum); ok {
+			f.Type = &EnumType{Name: info.Name, BitField: info.BitField}
+			return f
+		}
+	}
+
+	switch {
+	case xa.Type == "new_id" && xa.Interface == "":
+		// Type stays nil: wl_registry.bind-style requests expand this one
+		// arg into iface/version/id parameters instead of a declared type.
+	case xa.Type == "object" || xa.Type == "new_id":
+		f.Type = &InterfaceType{Name: ctx.Name(xa.Interface)}
+	case xa.Type == "array":
+		f.Type = &ArrayType{}
+	default:
+		f.Type = &BaseType{Name: xa.Type}
+	}
+
+	return f
+}
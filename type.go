@@ -0,0 +1,68 @@
+package main
+
+// wlTypes maps Wayland's primitive wire types to their Go equivalents.
+// fixed maps to Fixed rather than float32: the wire value is a 24.8
+// signed fixed-point number, and a plain float32 has neither the right
+// precision nor an obvious wire encoding.
+var wlTypes = map[string]string{
+	"int":    "int32",
+	"uint":   "uint32",
+	"string": "string",
+	"fd":     "uintptr",
+	"fixed":  "Fixed",
+	"array":  "[]int32",
+}
+
+// Type is the Go-facing type of a request or event argument. Every concrete
+// implementation knows how to render itself as a Go type; field.go builds on
+// top of this to render full parameter/return/struct-field declarations.
+type Type interface {
+	// GoType returns the Go type used to declare a value of this type.
+	GoType() string
+}
+
+// BaseType is one of Wayland's primitive wire types: int, uint, string, fd
+// or fixed.
+type BaseType struct {
+	Name string // wayland wire type name
+}
+
+func (t *BaseType) GoType() string {
+	return wlTypes[t.Name]
+}
+
+// InterfaceType references another protocol interface, resolved against the
+// Context symbol table at translation time. Name is empty for the
+// unresolved "generic object" case (an object arg with no interface
+// attribute), which renders as the Proxy interface instead of a concrete
+// pointer type.
+type InterfaceType struct {
+	Name string // Go identifier of the referenced interface, e.g. "Surface"
+}
+
+func (t *InterfaceType) GoType() string {
+	if t.Name == "" {
+		return "Proxy"
+	}
+	return "*" + t.Name
+}
+
+// EnumType references a (possibly bitfield) enum declared on some interface,
+// resolved against the Context symbol table. Name is the full Go identifier
+// of the enum type, e.g. "SurfaceError".
+type EnumType struct {
+	Name     string
+	BitField bool
+}
+
+func (t *EnumType) GoType() string {
+	return t.Name
+}
+
+// ArrayType is wl_array: a length-prefixed, 4-byte padded blob of opaque
+// data, surfaced to Go as a slice of int32.
+type ArrayType struct{}
+
+func (t *ArrayType) GoType() string {
+	return "[]int32"
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+)
+
+// wireSupportSrc is the one shared runtime-support file written alongside
+// the generated protocol files: the Fixed type and the push*/pop* encoders
+// that implement Wayland's wire format (32-bit aligned words; fixed as
+// 24.8 signed fixed-point; string/array as a uint32 length followed by
+// 4-byte padded data). Every generated request/Dispatch method calls into
+// these rather than re-deriving padding and alignment inline.
+const wireSupportSrc = `package %[1]s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	. "%[2]s"
+)
+
+// GeneratedCodeVersion identifies the wire format and API shape this batch
+// of generated code was produced against. A runtime wl package can compare
+// this against the version it supports and refuse to link against stale
+// generated code rather than fail in stranger ways at dispatch time.
+const GeneratedCodeVersion = 1
+
+// ErrVersionUnsupported is returned by a request method when the object's
+// negotiated interface version is lower than the version that introduced
+// the request.
+var ErrVersionUnsupported = errors.New("wl: request requires a higher interface version than negotiated")
+
+// Fixed is wayland's wire format for fractional values: a 24.8 signed
+// fixed-point number, transmitted as a plain int32.
+type Fixed int32
+
+func (f Fixed) Float64() float64 {
+	return float64(f) / 256.0
+}
+
+func FromFloat64(v float64) Fixed {
+	return Fixed(v * 256.0)
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+func proxyID(p Proxy) uint32 {
+	if p == nil {
+		return 0
+	}
+	return p.Id()
+}
+
+func pushUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func pushString(buf *bytes.Buffer, s string) {
+	data := append([]byte(s), 0)
+	pushUint32(buf, uint32(len(data)))
+	buf.Write(data)
+	buf.Write(make([]byte, align4(len(data))-len(data)))
+}
+
+func pushArray(buf *bytes.Buffer, arr []int32) {
+	pushUint32(buf, uint32(len(arr)*4))
+	for _, v := range arr {
+		pushUint32(buf, uint32(v))
+	}
+}
+
+func popUint32(data []byte, offset int) uint32 {
+	return binary.LittleEndian.Uint32(data[offset:])
+}
+
+func popString(data []byte, offset int) (string, int) {
+	l := int(popUint32(data, offset))
+	offset += 4
+	s := string(data[offset : offset+l-1]) // wire strings are nul-terminated
+	return s, offset + align4(l)
+}
+
+func popArray(data []byte, offset int) ([]int32, int) {
+	l := int(popUint32(data, offset))
+	offset += 4
+	arr := make([]int32, l/4)
+	for i := range arr {
+		arr[i] = int32(popUint32(data, offset+i*4))
+	}
+	return arr, offset + align4(l)
+}
+`
+
+// writeWireSupport writes the shared wire-format support file into outDir,
+// once per generator run.
+func writeWireSupport(outDir, outPackage, wlImport string) error {
+	src := fmt.Sprintf(wireSupportSrc, outPackage, wlImport)
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return fmt.Errorf("wire.go: %w", annotateFormatError([]byte(src), err))
+	}
+
+	return os.WriteFile(filepath.Join(outDir, "wire.go"), formatted, 0644)
+}